@@ -38,6 +38,10 @@ type GeneralDebiaser struct {
 	Window int
 	inds   []int
 	tmp    *mat64.Dense
+	// corrections is the fitted per-bin correction curve (in sorted order),
+	// fit once from values pooled across all sample columns and applied to
+	// every column alike, recorded by Debias for later use by Save and Apply.
+	corrections []float64
 }
 
 func (g *GeneralDebiaser) setTmp(r, c int) {
@@ -91,43 +95,45 @@ func (g *GeneralDebiaser) Unsort(mat *mat64.Dense) {
 	g.Vals = tmp
 }
 
-// Debias by subtracting moving median in each sample.
+// Debias by subtracting a moving median in each sample. The median at each
+// bin is fit once, pooling values across all sample columns in the window
+// rather than per column, so that the same correction curve is both applied
+// here and recorded in g.corrections for later reuse by Save/Apply.
 // It's assumed that g.Sort() has been called before this and that g.Unsort() will be called after.
 // It's also assumed that the values in mat have been scaled, for example by a `scaler.ZScore`.
 func (g *GeneralDebiaser) Debias(mat *mat64.Dense) {
 	r, c := mat.Dims()
-	col := make([]float64, r)
-	ins := make([]float64, 0, 2000)
-	outs := make([]float64, 0, 2000)
-	for sampleI := 0; sampleI < c; sampleI++ {
-		mat64.Col(col, sampleI, mat)
+	g.corrections = make([]float64, r)
 
-		mm := movingmedian.NewMovingMedian(g.Window)
-		mid := (g.Window-1)/2 + 1
-		for i := 0; i < mid; i++ {
-			mm.Push(col[i])
-			if sampleI == 0 {
-				ins = append(ins, col[i])
-			}
-		}
-		for i := 0; i < mid; i++ {
-			col[i] -= mm.Median()
-			if sampleI == 0 {
-				outs = append(outs, col[i])
-			}
+	mm := movingmedian.NewMovingMedian(g.Window * c)
+	mid := (g.Window-1)/2 + 1
+	push := func(i int) {
+		for _, v := range mat.RawRowView(i) {
+			mm.Push(v)
 		}
+	}
 
-		var i int
-		for i = mid; i < len(col)-mid; i++ {
-			mm.Push(col[i+mid])
-			col[i] -= mm.Median()
-			if sampleI == 0 {
-				outs = append(outs, col[i])
-				ins = append(ins, col[i])
-			}
-		}
-		for ; i < len(col); i++ {
-			col[i] -= mm.Median()
+	for i := 0; i < mid; i++ {
+		push(i)
+	}
+	for i := 0; i < mid; i++ {
+		g.corrections[i] = mm.Median()
+	}
+
+	var i int
+	for i = mid; i < r-mid; i++ {
+		push(i + mid)
+		g.corrections[i] = mm.Median()
+	}
+	for ; i < r; i++ {
+		g.corrections[i] = mm.Median()
+	}
+
+	col := make([]float64, r)
+	for sampleI := 0; sampleI < c; sampleI++ {
+		mat64.Col(col, sampleI, mat)
+		for i := range col {
+			col[i] -= g.corrections[i]
 		}
 		mat.SetCol(sampleI, col)
 	}
@@ -139,14 +145,25 @@ type ChunkDebiaser struct {
 	// E.g. if this is 0.1 then all values from 0.25-0.35 will be normalized to the median of
 	// Depths occuring in that range.
 	ScoreWindow float64
+
+	// chunkStarts[i] is the Vals value at the start of chunk i, and
+	// chunkMedians[i] is the fitted median for that chunk, pooled across all
+	// sample columns and applied to every column alike, recorded by Debias
+	// for later use by Save and Apply.
+	chunkStarts  []float64
+	chunkMedians []float64
 }
 
+// Debias normalizes each bin by the median of its chunk (the range of Vals
+// within cd.ScoreWindow of the chunk's start). The median for a chunk is fit
+// once, pooling values across all sample columns in that chunk, so that the
+// same correction is both applied here and recorded in cd.chunkMedians for
+// later reuse by Save/Apply.
 func (cd *ChunkDebiaser) Debias(mat *mat64.Dense) {
 	if cd.ScoreWindow == 0 {
 		panic("must set ChunkDebiaser.ScoreWindow")
 	}
-	r, c := mat.Dims()
-	col := make([]float64, r)
+	_, c := mat.Dims()
 
 	slices := make([]int, 1, 100)
 	v0 := cd.Vals[0]
@@ -157,30 +174,63 @@ func (cd *ChunkDebiaser) Debias(mat *mat64.Dense) {
 		}
 	}
 	slices = append(slices, len(cd.Vals))
-	dpSubset := make([]float64, 0, len(cd.Vals))
 
-	for sampleI := 0; sampleI < c; sampleI++ {
-		mat64.Col(col, sampleI, mat)
-		for i := 1; i < len(slices); i++ {
-			si, ei := slices[i-1], slices[i]
-			dpSubset = dpSubset[:(ei - si)]
-			copy(dpSubset, col[si:ei])
-			sort.Float64s(dpSubset)
-			median := dpSubset[(ei-si)/2]
+	cd.chunkStarts = make([]float64, len(slices)-1)
+	cd.chunkMedians = make([]float64, len(slices)-1)
+	for i := 1; i < len(slices); i++ {
+		cd.chunkStarts[i-1] = cd.Vals[slices[i-1]]
+	}
 
+	pool := make([]float64, 0, len(cd.Vals)*c)
+	for i := 1; i < len(slices); i++ {
+		si, ei := slices[i-1], slices[i]
+		pool = pool[:0]
+		for sampleI := 0; sampleI < c; sampleI++ {
 			for j := si; j < ei; j++ {
-				col[j] /= median
+				pool = append(pool, mat.At(j, sampleI))
+			}
+		}
+		sort.Float64s(pool)
+		median := pool[len(pool)/2]
+		cd.chunkMedians[i-1] = median
+
+		for sampleI := 0; sampleI < c; sampleI++ {
+			for j := si; j < ei; j++ {
+				mat.Set(j, sampleI, mat.At(j, sampleI)/median)
 			}
 		}
-		mat.SetCol(sampleI, col)
 	}
 }
 
 type SVD struct {
 	MinVariancePct float64
+	// MaxComponents, if > 0, switches Debias to a randomized SVD path: a
+	// rank-(MaxComponents+Oversample) approximation is computed instead of
+	// a full thin SVD, which is impractical on cohorts with thousands of
+	// samples and hundreds of thousands of bins.
+	MaxComponents int
+	// Oversample is the extra number of components (p, in the randomized
+	// SVD literature) computed beyond MaxComponents to stabilize the
+	// approximation. Defaults to 10 if left at zero.
+	Oversample int
+	// AutoSelect, when using the randomized path, picks the number of
+	// removed (bias) components via a Marchenko-Pastur-style noise
+	// threshold instead of the hard MinVariancePct percentage.
+	AutoSelect bool
+
+	// u holds the left singular vectors (bin space) and n the number of
+	// leading components treated as bias, recorded by Debias for later use
+	// by Save and Apply.
+	u *mat64.Dense
+	n int
 }
 
 func (isvd *SVD) Debias(mat *mat64.Dense) {
+	if isvd.MaxComponents > 0 {
+		isvd.debiasRandomized(mat)
+		return
+	}
+
 	var svd mat64.SVD
 	if ok := svd.Factorize(mat, matrix.SVDThin); !ok {
 		panic("error with SVD")
@@ -203,6 +253,42 @@ func (isvd *SVD) Debias(mat *mat64.Dense) {
 		sigma.Set(i, i, s[i])
 	}
 	mat.Product(u, sigma, v.T())
+
+	isvd.u, isvd.n = u, n
+}
+
+// debiasRandomized removes bias components using a randomized, truncated
+// SVD rather than a full thin SVD. The number of removed components is
+// isvd.MaxComponents, or, if AutoSelect is set, a Marchenko-Pastur noise
+// threshold applied to the computed singular values. Like SVD.Apply, only
+// the projection onto those leading components is subtracted from mat;
+// mat is not reconstructed from the (deliberately truncated) computed basis,
+// which would discard all signal outside it.
+func (isvd *SVD) debiasRandomized(mat *mat64.Dense) {
+	m, dims := mat.Dims()
+	oversample := isvd.Oversample
+	if oversample <= 0 {
+		oversample = 10
+	}
+
+	s, u, _ := randomizedSVD(mat, isvd.MaxComponents, oversample)
+
+	n := isvd.MaxComponents
+	if isvd.AutoSelect {
+		n = autoSelect(s, m, dims)
+	}
+	if n > len(s) {
+		n = len(s)
+	}
+	log.Printf("randomized svd: removing %d of %d computed components", n, len(s))
+
+	un := mat64.DenseCopyOf(u.View(0, 0, m, n))
+	var proj, removal mat64.Dense
+	proj.Mul(un.T(), mat)
+	removal.Mul(un, &proj)
+	mat.Sub(mat, &removal)
+
+	isvd.u, isvd.n = u, n
 }
 
 func extractSVD(svd *mat64.SVD) (s []float64, u, v *mat64.Dense) {
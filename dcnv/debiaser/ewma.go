@@ -0,0 +1,87 @@
+package debiaser
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// EWMADebiaser is a SortedDebiaser that subtracts an exponentially weighted
+// moving average (and, optionally, divides by an exponentially weighted
+// moving standard deviation) along the covariate-sorted axis, instead of the
+// fixed-window moving median used by GeneralDebiaser.Debias. Unlike a moving
+// median, an EWMA has no hard window edge, so it needs no `mid` boundary
+// handling at the start/end of the sorted axis.
+//
+// Usage is the same as GeneralDebiaser: call Sort(), then Debias(), then
+// Unsort().
+type EWMADebiaser struct {
+	GeneralDebiaser
+	// Halflife is the number of bins after which the weight of a past value
+	// decays to half. It is converted to a decay factor Alpha internally.
+	Halflife float64
+	// Warmup is the number of leading bins used to seed the moving average
+	// (and variance) with a simple mean before the EWMA recursion kicks in.
+	Warmup int
+	// Variance, if true, also divides by an EWMA-based standard deviation.
+	Variance bool
+}
+
+func (e *EWMADebiaser) alpha() float64 {
+	if e.Halflife <= 0 {
+		panic("EWMADebiaser: must set Halflife > 0")
+	}
+	return 1 - math.Pow(0.5, 1/e.Halflife)
+}
+
+// Debias by subtracting an EWMA (and, if Variance is set, dividing by an EWMA
+// standard deviation) in each sample column.
+// It's assumed that e.Sort() has been called before this and that e.Unsort()
+// will be called after.
+func (e *EWMADebiaser) Debias(mat *mat64.Dense) {
+	r, c := mat.Dims()
+	alpha := e.alpha()
+	warmup := e.Warmup
+	if warmup <= 0 {
+		warmup = 1
+	}
+	if warmup > r {
+		warmup = r
+	}
+	col := make([]float64, r)
+	for sampleI := 0; sampleI < c; sampleI++ {
+		mat64.Col(col, sampleI, mat)
+
+		var mu float64
+		for i := 0; i < warmup; i++ {
+			mu += col[i]
+		}
+		mu /= float64(warmup)
+
+		var variance float64
+		if e.Variance {
+			for i := 0; i < warmup; i++ {
+				d := col[i] - mu
+				variance += d * d
+			}
+			variance /= float64(warmup)
+		}
+
+		for i := 0; i < r; i++ {
+			v := col[i]
+			if e.Variance {
+				sigma := math.Sqrt(variance)
+				if sigma == 0 {
+					sigma = 1
+				}
+				col[i] = (v - mu) / sigma
+				d := v - mu
+				variance += alpha * (d*d - variance)
+			} else {
+				col[i] = v - mu
+			}
+			mu += alpha * (v - mu)
+		}
+		mat.SetCol(sampleI, col)
+	}
+}
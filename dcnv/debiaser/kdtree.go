@@ -0,0 +1,97 @@
+package debiaser
+
+import "sort"
+
+// kdNode is a node in a k-d tree built over rows of covariate values. index
+// refers back to the row's position in the original Vals/matrix.
+type kdNode struct {
+	index       int
+	left, right *kdNode
+}
+
+// buildKDTree builds a balanced k-d tree over the given row indices, cycling
+// the split axis through the covariate dimensions as depth increases.
+func buildKDTree(indices []int, vals [][]float64, depth int) *kdNode {
+	if len(indices) == 0 {
+		return nil
+	}
+	axis := depth % len(vals[indices[0]])
+	sort.Slice(indices, func(i, j int) bool {
+		return vals[indices[i]][axis] < vals[indices[j]][axis]
+	})
+	mid := len(indices) / 2
+	node := &kdNode{index: indices[mid]}
+	node.left = buildKDTree(indices[:mid], vals, depth+1)
+	node.right = buildKDTree(indices[mid+1:], vals, depth+1)
+	return node
+}
+
+// kNearest returns the indices of the k rows (excluding self) in vals nearest
+// to vals[self] in Euclidean covariate distance.
+func kNearest(root *kdNode, vals [][]float64, self, k int) []int {
+	best := make([]int, 0, k)
+	bestDist := make([]float64, 0, k)
+
+	var visit func(node *kdNode, depth int)
+	visit = func(node *kdNode, depth int) {
+		if node == nil {
+			return
+		}
+		if node.index != self {
+			d := sqDist(vals[self], vals[node.index])
+			if len(best) < k {
+				best = append(best, node.index)
+				bestDist = append(bestDist, d)
+			} else {
+				// replace the current worst neighbor if this one is closer.
+				worst, worstD := 0, bestDist[0]
+				for i, bd := range bestDist {
+					if bd > worstD {
+						worst, worstD = i, bd
+					}
+				}
+				if d < worstD {
+					best[worst] = node.index
+					bestDist[worst] = d
+				}
+			}
+		}
+
+		axis := depth % len(vals[self])
+		diff := vals[self][axis] - vals[node.index][axis]
+		near, far := node.left, node.right
+		if diff > 0 {
+			near, far = node.right, node.left
+		}
+		visit(near, depth+1)
+		// only descend into the far subtree if it could hold a closer point
+		// than our current worst (or we don't have k points yet).
+		if len(best) < k || diff*diff < maxDist(bestDist) {
+			visit(far, depth+1)
+		}
+	}
+	visit(root, 0)
+	return best
+}
+
+func sqDist(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+func maxDist(dists []float64) float64 {
+	if len(dists) == 0 {
+		return 0
+	}
+	m := dists[0]
+	for _, d := range dists[1:] {
+		if d > m {
+			m = d
+		}
+	}
+	return m
+}
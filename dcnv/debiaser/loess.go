@@ -0,0 +1,224 @@
+package debiaser
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// LoessDebiaser is a SortedDebiaser that fits a locally weighted regression
+// (LOESS) at each bin along the covariate-sorted axis, using a tricube
+// kernel over the nearest BandWidth neighbors, then subtracts the fitted
+// value from that bin. Compared to GeneralDebiaser's moving median, this
+// gives a bias curve that is continuous rather than step-like.
+//
+// Usage is the same as GeneralDebiaser: call Sort(), then Debias(), then
+// Unsort().
+type LoessDebiaser struct {
+	GeneralDebiaser
+	// BandWidth is the bandwidth in bins: the number of nearest neighbors
+	// (by position along the sorted axis) used to fit each local model.
+	// Named to avoid colliding with the embedded GeneralDebiaser.Window.
+	BandWidth int
+	// Degree is the order of the local polynomial: 0 = local mean,
+	// 1 = local linear, 2 = local quadratic.
+	Degree int
+	// Robust enables Cleveland's robust reweighting: after an initial fit,
+	// bisquare-reweight by residual and refit for RobustIters iterations.
+	Robust bool
+	// RobustIters is the number of robust reweighting iterations to run when
+	// Robust is set. Defaults to 3 if left at zero.
+	RobustIters int
+}
+
+// Debias by subtracting, from each bin, the value predicted by a local
+// weighted polynomial fit over its BandWidth nearest neighbors (by position
+// along the sorted axis).
+// It's assumed that l.Sort() has been called before this and that
+// l.Unsort() will be called after.
+func (l *LoessDebiaser) Debias(mat *mat64.Dense) {
+	if l.BandWidth <= l.Degree {
+		panic("LoessDebiaser: BandWidth must be greater than Degree")
+	}
+	r, c := mat.Dims()
+	x := make([]float64, r)
+	for i := range x {
+		x[i] = float64(i)
+	}
+
+	iters := l.RobustIters
+	if l.Robust && iters <= 0 {
+		iters = 3
+	}
+
+	col := make([]float64, r)
+	fitted := make([]float64, r)
+	weights := make([]float64, r)
+	for i := range weights {
+		weights[i] = 1
+	}
+	for sampleI := 0; sampleI < c; sampleI++ {
+		mat64.Col(col, sampleI, mat)
+
+		for i := 1; ; i++ {
+			l.fit(x, col, weights, fitted)
+			if !l.Robust || i > iters {
+				break
+			}
+			reweight(col, fitted, weights)
+		}
+
+		for i := range col {
+			col[i] -= fitted[i]
+		}
+		mat.SetCol(sampleI, col)
+
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+}
+
+// fit computes, for every point in x, the locally weighted polynomial fit of
+// degree l.Degree over its l.BandWidth nearest neighbors, writing the
+// result to fitted. robustWeights are Cleveland's robustness weights (all 1
+// on the first pass).
+func (l *LoessDebiaser) fit(x, y, robustWeights, fitted []float64) {
+	n := len(x)
+	half := l.BandWidth / 2
+	for i := 0; i < n; i++ {
+		lo := i - half
+		hi := lo + l.BandWidth
+		if lo < 0 {
+			hi -= lo
+			lo = 0
+		}
+		if hi > n {
+			lo -= hi - n
+			hi = n
+		}
+		if lo < 0 {
+			lo = 0
+		}
+		maxDist := math.Max(x[i]-x[lo], x[hi-1]-x[i])
+		if maxDist == 0 {
+			maxDist = 1
+		}
+
+		var w, wx, wy, wxx, wxy, wxxx, wxxy, wxxxx float64
+		for j := lo; j < hi; j++ {
+			d := math.Abs(x[j]-x[i]) / maxDist
+			tw := tricube(d) * robustWeights[j]
+			dx := x[j] - x[i]
+			w += tw
+			wx += tw * dx
+			wy += tw * y[j]
+			wxx += tw * dx * dx
+			wxy += tw * dx * y[j]
+			wxxx += tw * dx * dx * dx
+			wxxy += tw * dx * dx * y[j]
+			wxxxx += tw * dx * dx * dx * dx
+		}
+
+		switch {
+		case l.Degree <= 0 || w == 0:
+			fitted[i] = wy / w
+		case l.Degree == 1:
+			det := w*wxx - wx*wx
+			if det == 0 {
+				fitted[i] = wy / w
+				continue
+			}
+			b0 := (wxx*wy - wx*wxy) / det
+			fitted[i] = b0
+		default: // degree 2
+			// Solve the 3x3 normal equations for [b0, b1, b2] at dx=0, so
+			// the fitted value at x[i] is simply b0.
+			b0, ok := solveQuadratic(w, wx, wxx, wxxx, wxxxx, wy, wxy, wxxy)
+			if !ok {
+				fitted[i] = wy / w
+				continue
+			}
+			fitted[i] = b0
+		}
+	}
+}
+
+// solveQuadratic solves the 3x3 normal equations for a degree-2 weighted
+// local regression centered at dx=0, returning the intercept b0 (i.e. the
+// fitted value at the center point).
+func solveQuadratic(w, wx, wxx, wxxx, wxxxx, wy, wxy, wxxy float64) (float64, bool) {
+	// Normal equations, in matrix form, for y = b0 + b1*dx + b2*dx^2:
+	//   [ w    wx   wxx  ] [b0]   [wy ]
+	//   [ wx   wxx  wxxx ] [b1] = [wxy]
+	//   [ wxx  wxxx wxxxx] [b2]   [wxxy]
+	a := [3][3]float64{
+		{w, wx, wxx},
+		{wx, wxx, wxxx},
+		{wxx, wxxx, wxxxx},
+	}
+	b := [3]float64{wy, wxy, wxxy}
+	return solve3(a, b)
+}
+
+// solve3 solves the 3x3 linear system a*x = b via Cramer's rule, returning
+// x[0] and false if the system is singular.
+func solve3(a [3][3]float64, b [3]float64) (float64, bool) {
+	det := det3(a)
+	if det == 0 {
+		return 0, false
+	}
+	a0 := a
+	a0[0][0], a0[1][0], a0[2][0] = b[0], b[1], b[2]
+	return det3(a0) / det, true
+}
+
+func det3(a [3][3]float64) float64 {
+	return a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+}
+
+func tricube(d float64) float64 {
+	if d >= 1 {
+		return 0
+	}
+	t := 1 - d*d*d
+	return t * t * t
+}
+
+// reweight computes Cleveland's bisquare robustness weights from the
+// residuals of the previous fit, writing them into weights.
+func reweight(y, fitted, weights []float64) {
+	n := len(y)
+	resid := make([]float64, n)
+	for i := range resid {
+		resid[i] = y[i] - fitted[i]
+	}
+	mad := medianAbsDeviation(resid)
+	if mad == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		return
+	}
+	for i, r := range resid {
+		u := r / (6 * mad)
+		if u >= 1 || u <= -1 {
+			weights[i] = 0
+		} else {
+			t := 1 - u*u
+			weights[i] = t * t
+		}
+	}
+}
+
+func medianAbsDeviation(resid []float64) float64 {
+	abs := make([]float64, len(resid))
+	for i, r := range resid {
+		abs[i] = math.Abs(r)
+	}
+	sort.Float64s(abs)
+	return abs[len(abs)/2]
+}
@@ -0,0 +1,74 @@
+package debiaser
+
+import (
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// MultiCovariateDebiaser is a SortedDebiaser that corrects for several
+// covariates at once (e.g. GC, mappability, replication timing, fragment
+// length) in a single pass. Unlike GeneralDebiaser, which argsorts on a
+// single covariate and slides a 1-D window, MultiCovariateDebiaser builds a
+// k-d tree over the covariate rows in Vals and, for each bin, subtracts the
+// median of its K nearest neighbors in covariate space.
+//
+// Sort and Unsort are no-ops: ordering along a single axis has no meaning
+// once bins are compared by a multi-dimensional distance, so the neighbor
+// search is done directly against the unsorted Vals/matrix.
+type MultiCovariateDebiaser struct {
+	// Vals holds one row of covariates per bin, e.g. Vals[i] = []float64{gc, mappability}.
+	Vals [][]float64
+	// K is the number of nearest neighbors used to compute the local median.
+	K int
+
+	tree *kdNode
+}
+
+// Sort is a no-op for MultiCovariateDebiaser; it exists to satisfy SortedDebiaser.
+func (m *MultiCovariateDebiaser) Sort(mat *mat64.Dense) {}
+
+// Unsort is a no-op for MultiCovariateDebiaser; it exists to satisfy SortedDebiaser.
+func (m *MultiCovariateDebiaser) Unsort(mat *mat64.Dense) {}
+
+// Debias subtracts, from each bin, the median of its K nearest neighbors in
+// covariate space (computed independently per sample column).
+func (m *MultiCovariateDebiaser) Debias(mat *mat64.Dense) {
+	if m.K <= 0 {
+		panic("MultiCovariateDebiaser: must set K > 0")
+	}
+	r, c := mat.Dims()
+	if len(m.Vals) != r {
+		panic("MultiCovariateDebiaser: len(Vals) must match number of rows in mat")
+	}
+
+	if m.tree == nil {
+		indices := make([]int, r)
+		for i := range indices {
+			indices[i] = i
+		}
+		m.tree = buildKDTree(indices, m.Vals, 0)
+	}
+
+	neighbors := make([][]int, r)
+	for i := 0; i < r; i++ {
+		neighbors[i] = kNearest(m.tree, m.Vals, i, m.K)
+	}
+
+	col := make([]float64, r)
+	subset := make([]float64, m.K)
+	for sampleI := 0; sampleI < c; sampleI++ {
+		mat64.Col(col, sampleI, mat)
+		out := make([]float64, r)
+		for i := 0; i < r; i++ {
+			nbrs := neighbors[i]
+			subset = subset[:len(nbrs)]
+			for j, ni := range nbrs {
+				subset[j] = col[ni]
+			}
+			sort.Float64s(subset)
+			out[i] = col[i] - subset[len(subset)/2]
+		}
+		mat.SetCol(sampleI, out)
+	}
+}
@@ -0,0 +1,188 @@
+package debiaser
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Today, Debias both fits a correction from the input matrix and applies it
+// in the same call, so a new sample can only ever be normalized against
+// itself. Save/Load/Apply split those two steps apart: Debias is called
+// once on a reference panel, the fitted correction is persisted with Save,
+// and later runs Load it and call Apply on incoming samples one at a time
+// without recomputing anything from them.
+
+// generalModel is the gob-serializable fitted state of a GeneralDebiaser.
+type generalModel struct {
+	Vals        []float64
+	Window      int
+	Corrections []float64
+}
+
+// Save persists the correction curve fitted by the most recent Debias call.
+func (g *GeneralDebiaser) Save(w io.Writer) error {
+	if g.corrections == nil {
+		return errNotFitted
+	}
+	return gob.NewEncoder(w).Encode(generalModel{
+		Vals:        g.Vals,
+		Window:      g.Window,
+		Corrections: g.corrections,
+	})
+}
+
+// Load restores a correction curve previously written by Save, so that
+// Apply can be called without a preceding Debias.
+func (g *GeneralDebiaser) Load(r io.Reader) error {
+	var m generalModel
+	if err := gob.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	g.Vals = m.Vals
+	g.Window = m.Window
+	g.corrections = m.Corrections
+	return nil
+}
+
+// Apply subtracts the fitted correction curve from mat without recomputing
+// it, so a new sample can be normalized against a reference panel fit by a
+// prior Debias/Load. mat is expected to already be in the sorted order used
+// to fit the model (see Sort).
+func (g *GeneralDebiaser) Apply(mat *mat64.Dense) {
+	if g.corrections == nil {
+		panic("Apply: no fitted model; call Debias or Load first")
+	}
+	r, c := mat.Dims()
+	if r != len(g.corrections) {
+		panic("Apply: mat has a different number of rows than the fitted model")
+	}
+	col := make([]float64, r)
+	for sampleI := 0; sampleI < c; sampleI++ {
+		mat64.Col(col, sampleI, mat)
+		for i := range col {
+			col[i] -= g.corrections[i]
+		}
+		mat.SetCol(sampleI, col)
+	}
+}
+
+// chunkModel is the gob-serializable fitted state of a ChunkDebiaser.
+type chunkModel struct {
+	Vals         []float64
+	ScoreWindow  float64
+	ChunkStarts  []float64
+	ChunkMedians []float64
+}
+
+// Save persists the per-chunk medians fitted by the most recent Debias call.
+func (cd *ChunkDebiaser) Save(w io.Writer) error {
+	if cd.chunkMedians == nil {
+		return errNotFitted
+	}
+	return gob.NewEncoder(w).Encode(chunkModel{
+		Vals:         cd.Vals,
+		ScoreWindow:  cd.ScoreWindow,
+		ChunkStarts:  cd.chunkStarts,
+		ChunkMedians: cd.chunkMedians,
+	})
+}
+
+// Load restores per-chunk medians (and the Vals they were fitted against)
+// previously written by Save, so that Apply can be called without a
+// preceding Debias.
+func (cd *ChunkDebiaser) Load(r io.Reader) error {
+	var m chunkModel
+	if err := gob.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	cd.Vals = m.Vals
+	cd.ScoreWindow = m.ScoreWindow
+	cd.chunkStarts = m.ChunkStarts
+	cd.chunkMedians = m.ChunkMedians
+	return nil
+}
+
+// Apply divides each bin of mat by the median of the chunk its covariate
+// value (cd.Vals) falls into, using the chunk boundaries and medians fitted
+// by a prior Debias/Load, rather than recomputing them from mat.
+func (cd *ChunkDebiaser) Apply(mat *mat64.Dense) {
+	if cd.chunkMedians == nil {
+		panic("Apply: no fitted model; call Debias or Load first")
+	}
+	r, c := mat.Dims()
+	if r != len(cd.Vals) {
+		panic("Apply: len(Vals) must match the number of rows in mat")
+	}
+	col := make([]float64, r)
+	for sampleI := 0; sampleI < c; sampleI++ {
+		mat64.Col(col, sampleI, mat)
+		chunk := 0
+		for i := 0; i < r; i++ {
+			for chunk < len(cd.chunkStarts)-1 && cd.Vals[i] >= cd.chunkStarts[chunk+1] {
+				chunk++
+			}
+			col[i] /= cd.chunkMedians[chunk]
+		}
+		mat.SetCol(sampleI, col)
+	}
+}
+
+// svdModel is the gob-serializable fitted state of an SVD debiaser: every
+// computed column of the bin-space basis U, flattened row-major, plus its
+// dimensions and the number N of leading columns to remove.
+type svdModel struct {
+	Rows, Cols int
+	UData      []float64
+	N          int
+}
+
+// Save persists the U basis and component count fitted by the most recent
+// Debias call.
+func (isvd *SVD) Save(w io.Writer) error {
+	if isvd.u == nil {
+		return errNotFitted
+	}
+	r, c := isvd.u.Dims()
+	data := make([]float64, r*c)
+	for i := 0; i < r; i++ {
+		copy(data[i*c:(i+1)*c], isvd.u.RawRowView(i))
+	}
+	return gob.NewEncoder(w).Encode(svdModel{Rows: r, Cols: c, UData: data, N: isvd.n})
+}
+
+// Load restores a U basis and component count previously written by Save,
+// so that Apply can be called without a preceding Debias.
+func (isvd *SVD) Load(r io.Reader) error {
+	var m svdModel
+	if err := gob.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	isvd.u = mat64.NewDense(m.Rows, m.Cols, m.UData)
+	isvd.n = m.N
+	return nil
+}
+
+// Apply removes the fitted bias components from mat by projecting each
+// sample column onto the leading N columns of the stored U basis and
+// subtracting that projection, without recomputing an SVD from mat itself.
+func (isvd *SVD) Apply(mat *mat64.Dense) {
+	if isvd.u == nil {
+		panic("Apply: no fitted model; call Debias or Load first")
+	}
+	m, _ := mat.Dims()
+	ur, _ := isvd.u.Dims()
+	if m != ur {
+		panic("Apply: mat has a different number of rows than the fitted model")
+	}
+	un := mat64.DenseCopyOf(isvd.u.View(0, 0, ur, isvd.n))
+
+	var proj, removal mat64.Dense
+	proj.Mul(un.T(), mat)
+	removal.Mul(un, &proj)
+	mat.Sub(mat, &removal)
+}
+
+var errNotFitted = errors.New("Save: no fitted model; call Debias first")
@@ -0,0 +1,139 @@
+package debiaser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// approxEqual reports whether a and b have the same dimensions and agree
+// element-wise within tol.
+func approxEqual(a, b *mat64.Dense, tol float64) bool {
+	ar, ac := a.Dims()
+	br, bc := b.Dims()
+	if ar != br || ac != bc {
+		return false
+	}
+	for i := 0; i < ar; i++ {
+		for j := 0; j < ac; j++ {
+			d := a.At(i, j) - b.At(i, j)
+			if d < -tol || d > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestGeneralDebiaserSaveLoadApplyRoundTrip(t *testing.T) {
+	vals := []float64{0.8, 0.3, 0.5, 0.1, 0.7, 0.2, 0.6, 0.4}
+	data := []float64{
+		1, 8,
+		2, 7,
+		3, 6,
+		4, 5,
+		5, 4,
+		6, 3,
+		7, 2,
+		8, 1,
+	}
+
+	mat := mat64.NewDense(8, 2, append([]float64(nil), data...))
+	g := &GeneralDebiaser{Vals: append([]float64(nil), vals...), Window: 3}
+	g.Sort(mat)
+	g.Debias(mat)
+	g.Unsort(mat)
+	want := mat64.DenseCopyOf(mat)
+
+	var buf bytes.Buffer
+	if err := g.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	mat2 := mat64.NewDense(8, 2, append([]float64(nil), data...))
+	g2 := &GeneralDebiaser{}
+	if err := g2.Load(&buf); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	g2.Sort(mat2)
+	g2.Apply(mat2)
+	g2.Unsort(mat2)
+
+	if !approxEqual(want, mat2, 1e-9) {
+		t.Errorf("Apply after Load did not match Debias:\nwant:\n%v\ngot:\n%v", want, mat2)
+	}
+}
+
+func TestChunkDebiaserSaveLoadApplyRoundTrip(t *testing.T) {
+	vals := []float64{0.05, 0.1, 0.15, 0.3, 0.35, 0.4, 0.6, 0.65}
+	data := []float64{
+		10, 20,
+		12, 22,
+		11, 18,
+		20, 40,
+		22, 38,
+		21, 42,
+		30, 60,
+		32, 58,
+	}
+
+	mat := mat64.NewDense(8, 2, append([]float64(nil), data...))
+	cd := &ChunkDebiaser{
+		GeneralDebiaser: GeneralDebiaser{Vals: append([]float64(nil), vals...)},
+		ScoreWindow:     0.1,
+	}
+	cd.Sort(mat)
+	cd.Debias(mat)
+	cd.Unsort(mat)
+	want := mat64.DenseCopyOf(mat)
+
+	var buf bytes.Buffer
+	if err := cd.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	mat2 := mat64.NewDense(8, 2, append([]float64(nil), data...))
+	cd2 := &ChunkDebiaser{}
+	if err := cd2.Load(&buf); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cd2.Sort(mat2)
+	cd2.Apply(mat2)
+	cd2.Unsort(mat2)
+
+	if !approxEqual(want, mat2, 1e-9) {
+		t.Errorf("Apply after Load did not match Debias:\nwant:\n%v\ngot:\n%v", want, mat2)
+	}
+}
+
+func TestSVDSaveLoadApplyRoundTrip(t *testing.T) {
+	data := []float64{
+		1, 2, 3, 9,
+		2, 4, 6, 1,
+		3, 6, 9, 4,
+		4, 8, 12, 2,
+		5, 1, 2, 7,
+	}
+
+	mat := mat64.NewDense(5, 4, append([]float64(nil), data...))
+	isvd := &SVD{MinVariancePct: 5}
+	isvd.Debias(mat)
+	want := mat64.DenseCopyOf(mat)
+
+	var buf bytes.Buffer
+	if err := isvd.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	mat2 := mat64.NewDense(5, 4, append([]float64(nil), data...))
+	isvd2 := &SVD{}
+	if err := isvd2.Load(&buf); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	isvd2.Apply(mat2)
+
+	if !approxEqual(want, mat2, 1e-6) {
+		t.Errorf("Apply after Load did not match Debias:\nwant:\n%v\ngot:\n%v", want, mat2)
+	}
+}
@@ -0,0 +1,87 @@
+package debiaser
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/gonum/matrix"
+	"github.com/gonum/matrix/mat64"
+)
+
+// randomizedSVD computes a rank-(k+p) approximate thin SVD of mat using the
+// Halko/Martinsson/Tropp randomized range-finder algorithm: draw a Gaussian
+// test matrix Omega, form Y = A*Omega, orthonormalize Y via QR to get a
+// basis Q of range(A), project B = Q^T*A, take a small SVD of B, and lift
+// back U = Q*Uhat. Only the leading k singular values and vectors of that
+// approximation are returned. This avoids the O(min(m,n)^2 * max(m,n)) cost of a full
+// thin SVD, which is impractical on cohorts with thousands of samples and
+// hundreds of thousands of bins.
+func randomizedSVD(mat *mat64.Dense, k, p int) (s []float64, u, v *mat64.Dense) {
+	m, n := mat.Dims()
+	rank := k + p
+	if rank > n {
+		rank = n
+	}
+
+	omega := mat64.NewDense(n, rank, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < rank; j++ {
+			omega.Set(i, j, rand.NormFloat64())
+		}
+	}
+
+	var y mat64.Dense
+	y.Mul(mat, omega)
+
+	var qr mat64.QR
+	qr.Factorize(&y)
+	var q mat64.Dense
+	q.QFromQR(&qr)
+	if _, qc := q.Dims(); qc > rank {
+		q = *mat64.DenseCopyOf(q.View(0, 0, m, rank))
+	}
+
+	var b mat64.Dense
+	b.Mul(q.T(), mat)
+
+	var svd mat64.SVD
+	if ok := svd.Factorize(&b, matrix.SVDThin); !ok {
+		panic("error with randomized SVD")
+	}
+	bs, bu, bv := extractSVD(&svd)
+
+	var uFull mat64.Dense
+	uFull.Mul(&q, bu)
+
+	if k < len(bs) {
+		bs = bs[:k]
+		uFull = *mat64.DenseCopyOf(uFull.View(0, 0, m, k))
+		bv = mat64.DenseCopyOf(bv.View(0, 0, n, k))
+	}
+	return bs, &uFull, bv
+}
+
+// autoSelect picks the number of leading components to treat as bias/noise
+// using a Marchenko-Pastur-style threshold: a singular value is kept as
+// signal to be removed if it exceeds (1+sqrt(m/n))*sigmaNoise, where
+// sigmaNoise is estimated as the median singular value divided by sqrt(n).
+func autoSelect(s []float64, m, n int) int {
+	if len(s) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), s...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	sigmaNoise := median / math.Sqrt(float64(n))
+	threshold := (1 + math.Sqrt(float64(m)/float64(n))) * sigmaNoise
+
+	var count int
+	for _, v := range s {
+		if v <= threshold {
+			break
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,133 @@
+// Package scaler provides implementations that standardize the columns of a
+// mat64.Dense in place, for use before/after a debiaser.Debiaser so that bias
+// removal operates on a comparable scale across samples.
+package scaler
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Scaler standardizes (Scale) and reverts (Unscale) the columns of a matrix in place.
+type Scaler interface {
+	Scale(*mat64.Dense)
+	Unscale(*mat64.Dense)
+}
+
+// RobustScaler standardizes each column using a trimmed mean and standard
+// deviation computed from the values between LowerQuantile and UpperQuantile,
+// then divides by the interquartile range. This is more resilient than a
+// plain z-score to outlier bins (e.g. repeats, low-mappability regions).
+//
+// When LowerQuantile and UpperQuantile are both left at zero they default to
+// 0.05 and 0.95; if either is set, both are used as given. Call Scale before GeneralDebiaser.Debias and Unscale after
+// Unsort to restore the original scale.
+type RobustScaler struct {
+	LowerQuantile float64
+	UpperQuantile float64
+
+	mus    []float64
+	sigmas []float64
+	iqrs   []float64
+}
+
+func (rs *RobustScaler) quantiles() (float64, float64) {
+	lo, hi := rs.LowerQuantile, rs.UpperQuantile
+	if lo == 0 && hi == 0 {
+		lo, hi = 0.05, 0.95
+	}
+	return lo, hi
+}
+
+// Scale standardizes each column of mat in place and caches the per-column
+// mu, sigma, and IQR so that Unscale can later restore the original scale.
+func (rs *RobustScaler) Scale(mat *mat64.Dense) {
+	r, c := mat.Dims()
+	lo, hi := rs.quantiles()
+
+	rs.mus = make([]float64, c)
+	rs.sigmas = make([]float64, c)
+	rs.iqrs = make([]float64, c)
+
+	col := make([]float64, r)
+	sorted := make([]float64, r)
+	for j := 0; j < c; j++ {
+		mat64.Col(col, j, mat)
+		copy(sorted, col)
+		sort.Float64s(sorted)
+
+		loI := int(lo * float64(r))
+		hiI := int(hi * float64(r))
+		if hiI <= loI {
+			hiI = loI + 1
+		}
+		if hiI > r {
+			hiI = r
+		}
+		trimmed := sorted[loI:hiI]
+
+		mu := mean(trimmed)
+		sigma := stddev(trimmed, mu)
+		iqr := quantileAt(sorted, 0.75) - quantileAt(sorted, 0.25)
+		if iqr == 0 {
+			iqr = 1
+		}
+
+		rs.mus[j] = mu
+		rs.sigmas[j] = sigma
+		rs.iqrs[j] = iqr
+
+		for i, v := range col {
+			col[i] = (v - mu) / sigma / iqr
+		}
+		mat.SetCol(j, col)
+	}
+}
+
+// Unscale reverts the standardization applied by the most recent Scale call.
+func (rs *RobustScaler) Unscale(mat *mat64.Dense) {
+	if rs.mus == nil {
+		panic("unscale: must call Scale first")
+	}
+	r, c := mat.Dims()
+	col := make([]float64, r)
+	for j := 0; j < c; j++ {
+		mat64.Col(col, j, mat)
+		for i, v := range col {
+			col[i] = v*rs.iqrs[j]*rs.sigmas[j] + rs.mus[j]
+		}
+		mat.SetCol(j, col)
+	}
+}
+
+func mean(vals []float64) float64 {
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
+
+func stddev(vals []float64, mu float64) float64 {
+	var sum float64
+	for _, v := range vals {
+		d := v - mu
+		sum += d * d
+	}
+	variance := sum / float64(len(vals))
+	if variance <= 0 {
+		return 1
+	}
+	return math.Sqrt(variance)
+}
+
+func quantileAt(sorted []float64, q float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	i := int(q * float64(n-1))
+	return sorted[i]
+}